@@ -0,0 +1,536 @@
+// Package alloc implements a small declarative expression language for describing a target
+// portfolio allocation, e.g.:
+//
+//	allocate 100% {
+//		60% to stocks { 40% VTI, 40% VXUS, 20% VWO },
+//		40% to bonds { 70% BND, 30% BNDX },
+//		remainder to *CASH*
+//	}
+//
+// Parse evaluates such an expression, using exact rational arithmetic throughout, into a flat
+// []portfolio.Position whose percentages are guaranteed to add up to exactly 100, for use with
+// portfolio.Account.SetDesired or portfolio.Household.SetDesired. A "remainder" entry whose
+// siblings already add up to 100% (as *CASH* in the example above) is still emitted, with a 0%
+// percentage, which SetDesired rejects with portfolio.ErrBadPct.
+package alloc
+
+import (
+	"fmt"
+	"math/big"
+	"unicode"
+
+	"github.com/nu11ptr/decimal"
+	portfolio "github.com/nu11ptr/portfolio-swap"
+)
+
+// SyntaxError reports a parse or evaluation error together with the source position that caused
+// it
+type SyntaxError struct {
+	Line, Col int
+	Msg       string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("alloc: %d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// pctPrecision is the number of decimal digits kept when a leaf's rational percentage is
+// rendered to a decimal.Decimal
+const pctPrecision = 10
+
+// --- lexer ---
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokLBrace
+	tokRBrace
+	tokComma
+	tokSemi
+	tokEquals
+	tokSlash
+	tokPlus
+	tokMinus
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind      tokenKind
+	text      string
+	isPercent bool // a tokNumber immediately followed by '%'
+	line, col int
+}
+
+type lexer struct {
+	src       []rune
+	pos       int
+	line, col int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: []rune(src), line: 1, col: 1}
+}
+
+func (l *lexer) peek() rune {
+	if l.pos >= len(l.src) {
+		return 0
+	}
+	return l.src[l.pos]
+}
+
+func (l *lexer) advance() rune {
+	r := l.src[l.pos]
+	l.pos++
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+// isIdentStart allows '*' so that the *CASH* sentinel symbol lexes as a single identifier; the
+// grammar has no use for '*' as a multiplication operator so there is no ambiguity
+func isIdentStart(r rune) bool { return unicode.IsLetter(r) || r == '_' || r == '*' }
+func isIdentPart(r rune) bool  { return isIdentStart(r) || unicode.IsDigit(r) }
+
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		switch r := l.peek(); {
+		case unicode.IsSpace(r):
+			l.advance()
+		case r == '#':
+			for l.pos < len(l.src) && l.peek() != '\n' {
+				l.advance()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpaceAndComments()
+	line, col := l.line, l.col
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: line, col: col}, nil
+	}
+
+	switch r := l.peek(); {
+	case unicode.IsDigit(r):
+		start := l.pos
+		for l.pos < len(l.src) && (unicode.IsDigit(l.peek()) || l.peek() == '.') {
+			l.advance()
+		}
+		text := string(l.src[start:l.pos])
+		isPct := false
+		if l.peek() == '%' {
+			l.advance()
+			isPct = true
+		}
+		return token{kind: tokNumber, text: text, isPercent: isPct, line: line, col: col}, nil
+	case isIdentStart(r):
+		start := l.pos
+		for l.pos < len(l.src) && isIdentPart(l.peek()) {
+			l.advance()
+		}
+		return token{kind: tokIdent, text: string(l.src[start:l.pos]), line: line, col: col}, nil
+	}
+
+	r := l.advance()
+	kinds := map[rune]tokenKind{
+		'{': tokLBrace, '}': tokRBrace, ',': tokComma, ';': tokSemi,
+		'=': tokEquals, '/': tokSlash, '+': tokPlus, '-': tokMinus,
+		'(': tokLParen, ')': tokRParen,
+	}
+	if kind, ok := kinds[r]; ok {
+		return token{kind: kind, text: string(r), line: line, col: col}, nil
+	}
+	return token{}, &SyntaxError{Line: line, Col: col, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
+
+// --- AST ---
+
+// group is a '{' ... '}' block: either the root of an allocate statement or a nested named group
+type group struct {
+	tok     token
+	entries []*entry
+}
+
+// entry is one "<portion> to <target>" clause within a group
+type entry struct {
+	tok         token
+	isRemainder bool
+	portion     *big.Rat // nil when isRemainder
+	sym         string
+	nested      *group // set when the target subdivides further
+}
+
+// --- parser ---
+
+type parser struct {
+	lex  *lexer
+	tok  token
+	vars map[string]*big.Rat
+}
+
+func newParser(src string) (*parser, error) {
+	p := &parser{lex: newLexer(src), vars: make(map[string]*big.Rat)}
+	return p, p.advance()
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	if p.tok.kind != kind {
+		return token{}, p.errorf("expected %s, got %q", what, p.tok.text)
+	}
+	tok := p.tok
+	return tok, p.advance()
+}
+
+func (p *parser) expectKeyword(kw string) error {
+	if p.tok.kind != tokIdent || p.tok.text != kw {
+		return p.errorf("expected %q, got %q", kw, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: p.tok.line, Col: p.tok.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) parseLet() error {
+	if err := p.advance(); err != nil { // consume "let"
+		return err
+	}
+	name, err := p.expect(tokIdent, "a variable name")
+	if err != nil {
+		return err
+	}
+	if _, err := p.expect(tokEquals, "'='"); err != nil {
+		return err
+	}
+	val, err := p.parseExpr()
+	if err != nil {
+		return err
+	}
+	if _, err := p.expect(tokSemi, "';'"); err != nil {
+		return err
+	}
+	p.vars[name.text] = val
+	return nil
+}
+
+// parseAllocate parses "allocate" [<portion>] "{" ... "}" and returns the root group together
+// with the portion of the whole that it represents (1, i.e. 100%, if omitted)
+func (p *parser) parseAllocate() (*group, *big.Rat, error) {
+	kw := p.tok
+	if err := p.advance(); err != nil { // consume "allocate"
+		return nil, nil, err
+	}
+
+	total := big.NewRat(1, 1)
+	if p.tok.kind != tokLBrace {
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, nil, err
+		}
+		total = v
+	}
+	if total.Cmp(one) != 0 {
+		got := new(big.Rat).Mul(total, big.NewRat(100, 1))
+		return nil, nil, &SyntaxError{Line: kw.line, Col: kw.col, Msg: fmt.Sprintf("\"allocate\" portion must be 100%%, got %s%%", got.FloatString(6))}
+	}
+
+	g, err := p.parseGroupBody()
+	return g, total, err
+}
+
+func (p *parser) parseGroupBody() (*group, error) {
+	lbrace, err := p.expect(tokLBrace, "'{'")
+	if err != nil {
+		return nil, err
+	}
+
+	g := &group{tok: lbrace}
+	for p.tok.kind != tokRBrace {
+		e, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		g.entries = append(g.entries, e)
+
+		if p.tok.kind != tokComma {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.expect(tokRBrace, "'}'")
+	return g, err
+}
+
+func (p *parser) parseEntry() (*entry, error) {
+	tok := p.tok
+
+	e := &entry{tok: tok}
+	if p.tok.kind == tokIdent && p.tok.text == "remainder" {
+		e.isRemainder = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	} else {
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		e.portion = v
+	}
+
+	// "to" is an optional connective word; "60% to stocks { ... }" and "40% VTI" are both valid
+	if p.tok.kind == tokIdent && p.tok.text == "to" {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := p.expect(tokIdent, "a symbol or group name")
+	if err != nil {
+		return nil, err
+	}
+	e.sym = target.text
+
+	if p.tok.kind == tokLBrace {
+		nested, err := p.parseGroupBody()
+		if err != nil {
+			return nil, err
+		}
+		e.nested = nested
+	}
+	return e, nil
+}
+
+// parseExpr implements portion arithmetic: +, -, / over percent literals, bare fractions (e.g.
+// 1/3) and variables, left-associative, with '/' binding tighter than '+'/'-'
+func (p *parser) parseExpr() (*big.Rat, error) {
+	v, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokPlus || p.tok.kind == tokMinus {
+		op := p.tok.kind
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if op == tokPlus {
+			v = new(big.Rat).Add(v, rhs)
+		} else {
+			v = new(big.Rat).Sub(v, rhs)
+		}
+	}
+	return v, nil
+}
+
+func (p *parser) parseTerm() (*big.Rat, error) {
+	v, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokSlash {
+		tok := p.tok
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		rhs, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		if rhs.Sign() == 0 {
+			return nil, &SyntaxError{Line: tok.line, Col: tok.col, Msg: "division by zero"}
+		}
+		v = new(big.Rat).Quo(v, rhs)
+	}
+	return v, nil
+}
+
+func (p *parser) parseFactor() (*big.Rat, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tokNumber:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, ok := new(big.Rat).SetString(tok.text)
+		if !ok {
+			return nil, &SyntaxError{Line: tok.line, Col: tok.col, Msg: fmt.Sprintf("invalid number %q", tok.text)}
+		}
+		if tok.isPercent {
+			v.Quo(v, big.NewRat(100, 1))
+		}
+		return v, nil
+	case tokIdent:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, ok := p.vars[tok.text]
+		if !ok {
+			return nil, &SyntaxError{Line: tok.line, Col: tok.col, Msg: fmt.Sprintf("undefined variable %q", tok.text)}
+		}
+		return v, nil
+	case tokLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		v, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(tokRParen, "')'")
+		return v, err
+	}
+	return nil, &SyntaxError{Line: tok.line, Col: tok.col, Msg: fmt.Sprintf("expected a number or variable, got %q", tok.text)}
+}
+
+// --- evaluator ---
+
+// ratPosition is a leaf position with its exact fraction of the whole portfolio
+type ratPosition struct {
+	sym string
+	pct *big.Rat
+}
+
+var one = big.NewRat(1, 1)
+
+// evalGroup resolves any "remainder" entry in g against its siblings, checks that the group's
+// portions add up to exactly 100%, and appends a ratPosition for every leaf reached, scaling each
+// entry's portion by parentPortion.
+func evalGroup(g *group, parentPortion *big.Rat, out *[]ratPosition) error {
+	if len(g.entries) == 0 {
+		return &SyntaxError{Line: g.tok.line, Col: g.tok.col, Msg: "group has no entries"}
+	}
+
+	sum := new(big.Rat)
+	var remainder *entry
+	for _, e := range g.entries {
+		if e.isRemainder {
+			if remainder != nil {
+				return &SyntaxError{Line: e.tok.line, Col: e.tok.col, Msg: "only one \"remainder\" entry is allowed per group"}
+			}
+			remainder = e
+			continue
+		}
+		sum.Add(sum, e.portion)
+	}
+
+	switch {
+	case remainder != nil:
+		if sum.Cmp(one) > 0 {
+			return &SyntaxError{Line: remainder.tok.line, Col: remainder.tok.col, Msg: "\"remainder\" has nothing left to allocate"}
+		}
+		remainder.portion = new(big.Rat).Sub(one, sum)
+	case sum.Cmp(one) != 0:
+		got := new(big.Rat).Mul(sum, big.NewRat(100, 1))
+		return &SyntaxError{Line: g.tok.line, Col: g.tok.col, Msg: fmt.Sprintf("group percentages must add up to 100%%, got %s%%", got.FloatString(6))}
+	}
+
+	for _, e := range g.entries {
+		childPortion := new(big.Rat).Mul(parentPortion, e.portion)
+		if e.nested != nil {
+			if err := evalGroup(e.nested, childPortion, out); err != nil {
+				return err
+			}
+			continue
+		}
+		*out = append(*out, ratPosition{sym: e.sym, pct: childPortion})
+	}
+	return nil
+}
+
+// toPositions renders each leaf's exact fraction to a decimal.Decimal percentage. The last leaf
+// absorbs whatever rounding was introduced by pctPrecision so the decimal percentages still add
+// up to exactly 100, matching the guarantee Parse makes internally with big.Rat.
+func toPositions(leaves []ratPosition) ([]portfolio.Position, error) {
+	hundred := big.NewRat(100, 1)
+	positions := make([]portfolio.Position, len(leaves))
+	running := new(big.Rat)
+
+	for i, leaf := range leaves {
+		var pctStr string
+		if i == len(leaves)-1 {
+			pctStr = new(big.Rat).Sub(hundred, running).FloatString(pctPrecision)
+		} else {
+			pctStr = new(big.Rat).Mul(leaf.pct, hundred).FloatString(pctPrecision)
+			rounded, _ := new(big.Rat).SetString(pctStr)
+			running.Add(running, rounded)
+		}
+
+		d, ok := decimal.New(pctStr)
+		if !ok {
+			return nil, fmt.Errorf("alloc: could not convert percentage for %q to a decimal", leaf.sym)
+		}
+
+		secType := portfolio.Stock
+		if leaf.sym == portfolio.CashSym {
+			secType = portfolio.Cash
+		}
+		positions[i] = portfolio.Position{Sym: leaf.sym, SecType: secType, Pct: *d}
+	}
+	return positions, nil
+}
+
+// Parse parses and evaluates src, returning the flat, fully-resolved []portfolio.Position it
+// describes.
+func Parse(src string) ([]portfolio.Position, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *group
+	var total *big.Rat
+	for p.tok.kind != tokEOF {
+		switch {
+		case p.tok.kind == tokIdent && p.tok.text == "let":
+			if err := p.parseLet(); err != nil {
+				return nil, err
+			}
+		case p.tok.kind == tokIdent && p.tok.text == "allocate":
+			if root != nil {
+				return nil, p.errorf("only one \"allocate\" block is supported")
+			}
+			root, total, err = p.parseAllocate()
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.errorf("expected \"let\" or \"allocate\", got %q", p.tok.text)
+		}
+	}
+	if root == nil {
+		return nil, p.errorf("missing \"allocate\" block")
+	}
+
+	var leaves []ratPosition
+	if err := evalGroup(root, total, &leaves); err != nil {
+		return nil, err
+	}
+	return toPositions(leaves)
+}
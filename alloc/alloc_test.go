@@ -0,0 +1,121 @@
+package alloc_test
+
+import (
+	"testing"
+
+	"github.com/nu11ptr/decimal"
+	portfolio "github.com/nu11ptr/portfolio-swap"
+	"github.com/nu11ptr/portfolio-swap/alloc"
+)
+
+func sumPct(t *testing.T, positions []portfolio.Position) *decimal.Decimal {
+	t.Helper()
+	total := decimal.NewInt(0)
+	for _, p := range positions {
+		total = total.Add(&p.Pct)
+	}
+	return total
+}
+
+func TestParseNestedGroupsAndRemainder(t *testing.T) {
+	src := `
+		allocate 100% {
+			60% to stocks { 40% VTI, 40% VXUS, 20% VWO },
+			40% to bonds { 70% BND, 30% BNDX },
+			remainder to *CASH*
+		}
+	`
+
+	positions, err := alloc.Parse(src)
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	want := map[string]bool{"VTI": true, "VXUS": true, "VWO": true, "BND": true, "BNDX": true, portfolio.CashSym: true}
+	if len(positions) != len(want) {
+		t.Fatalf("Got %d positions, expected %d", len(positions), len(want))
+	}
+	for _, p := range positions {
+		if !want[p.Sym] {
+			t.Errorf("unexpected symbol %q in output", p.Sym)
+		}
+		if p.Sym == portfolio.CashSym && p.SecType != portfolio.Cash {
+			t.Errorf("expected %s to have SecType Cash", portfolio.CashSym)
+		}
+	}
+
+	if !sumPct(t, positions).EQ(decimal.NewInt(100)) {
+		t.Errorf("Got total %v, expected exactly 100", sumPct(t, positions))
+	}
+}
+
+func TestParseVariablesAndRemainder(t *testing.T) {
+	src := `
+		let core = 80%;
+		allocate {
+			core to VT,
+			remainder to BND
+		}
+	`
+
+	positions, err := alloc.Parse(src)
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(positions) != 2 {
+		t.Fatalf("Got %d positions, expected 2", len(positions))
+	}
+	if !sumPct(t, positions).EQ(decimal.NewInt(100)) {
+		t.Errorf("Got total %v, expected exactly 100", sumPct(t, positions))
+	}
+}
+
+func TestParseThirdsFraction(t *testing.T) {
+	src := `allocate { 1/3 to A, 1/3 to B, 1/3 to C }`
+
+	positions, err := alloc.Parse(src)
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	if !sumPct(t, positions).EQ(decimal.NewInt(100)) {
+		t.Errorf("Got total %v, expected exactly 100 despite 1/3 rounding", sumPct(t, positions))
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"Overflow", `allocate { 60% to A, 60% to B }`},
+		{"Underflow", `allocate { 40% to A, 40% to B }`},
+		{"UndefinedVar", `allocate { core to A, remainder to B }`},
+		{"MissingTarget", `allocate { 100% }`},
+		{"DoubleRemainder", `allocate { remainder to A, remainder to B }`},
+		{"DivideByZero", `allocate { 100%/0 to A }`},
+		{"RootPortionNot100", `allocate 80% { 50% to A, 50% to B }`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := alloc.Parse(test.src); err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorPosition(t *testing.T) {
+	_, err := alloc.Parse("allocate { 50% to A }")
+	if err == nil {
+		t.Fatal("expected an error, got nil")
+	}
+	synErr, ok := err.(*alloc.SyntaxError)
+	if !ok {
+		t.Fatalf("Got error of type %T, expected *alloc.SyntaxError", err)
+	}
+	if synErr.Line != 1 {
+		t.Errorf("Got line %d, expected 1", synErr.Line)
+	}
+}
@@ -0,0 +1,122 @@
+package portfolio
+
+import (
+	"sync"
+
+	"github.com/nu11ptr/decimal"
+)
+
+// FXTable stores directed currency conversion rates and resolves a rate between any two
+// connected currencies, chaining through intermediates when no direct rate is set.
+type FXTable struct {
+	mut   sync.Mutex
+	rates map[string]map[string]decimal.Decimal
+}
+
+// NewFXTable creates an empty FXTable
+func NewFXTable() *FXTable {
+	return &FXTable{rates: make(map[string]map[string]decimal.Decimal)}
+}
+
+// SetRate records that one unit of from is worth rate units of to
+func (fx *FXTable) SetRate(from, to string, rate *decimal.Decimal) error {
+	if from == "" || to == "" {
+		return ErrBadSym
+	}
+	if rate.LTE(zero) {
+		return ErrBadPrice
+	}
+
+	fx.mut.Lock()
+	defer fx.mut.Unlock()
+
+	if fx.rates[from] == nil {
+		fx.rates[from] = make(map[string]decimal.Decimal)
+	}
+	fx.rates[from][to] = *rate
+	return nil
+}
+
+// CrossRate returns the rate to multiply an amount in from by to convert it to to, walking a
+// chain of directed rates (e.g. JPY->USD->EUR) via breadth-first search when no direct rate is
+// set. It returns ErrNoFXPath if the two currencies are not connected.
+func (fx *FXTable) CrossRate(from, to string) (*decimal.Decimal, error) {
+	if from == to {
+		return one, nil
+	}
+
+	fx.mut.Lock()
+	defer fx.mut.Unlock()
+
+	type step struct {
+		ccy  string
+		rate *decimal.Decimal
+	}
+
+	visited := map[string]bool{from: true}
+	queue := []step{{from, one}}
+
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		for next, rate := range fx.rates[cur.ccy] {
+			if visited[next] {
+				continue
+			}
+			combined := cur.rate.Mul(&rate)
+			if next == to {
+				return combined, nil
+			}
+			visited[next] = true
+			queue = append(queue, step{next, combined})
+		}
+	}
+	return nil, ErrNoFXPath
+}
+
+// SetFXTable sets the FX table the account uses to convert position values into its report
+// currency
+func (a *Account) SetFXTable(fx *FXTable) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.fxTable = fx
+}
+
+// ReportCurrency sets the currency portfolio value and drift are computed in
+func (a *Account) ReportCurrency(ccy string) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.reportCcy = ccy
+}
+
+// reportCurrency returns the account's report currency, defaulting to USD. The caller must hold
+// a.mut.
+func (a *Account) reportCurrency() string {
+	if a.reportCcy == "" {
+		return defaultCurrency
+	}
+	return a.reportCcy
+}
+
+// convert converts amount, denominated in ccy, into the account's report currency. The caller
+// must hold a.mut.
+func (a *Account) convert(amount *decimal.Decimal, ccy string) (*decimal.Decimal, error) {
+	report := a.reportCurrency()
+	if ccy == "" {
+		ccy = defaultCurrency
+	}
+	if ccy == report {
+		return amount, nil
+	}
+	if a.fxTable == nil {
+		return nil, ErrNoFXPath
+	}
+	rate, err := a.fxTable.CrossRate(ccy, report)
+	if err != nil {
+		return nil, err
+	}
+	return amount.Mul(rate), nil
+}
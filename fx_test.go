@@ -0,0 +1,106 @@
+package portfolio_test
+
+import (
+	"testing"
+
+	portfolio "github.com/nu11ptr/portfolio-swap"
+)
+
+func TestFXTableCrossRateChain(t *testing.T) {
+	fx := portfolio.NewFXTable()
+	if err := fx.SetRate("JPY", "USD", mustDecimal(t, "0.0067")); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := fx.SetRate("USD", "EUR", mustDecimal(t, "0.92")); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	rate, err := fx.CrossRate("JPY", "EUR")
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if !rate.EQ(mustDecimal(t, "0.006164")) {
+		t.Error("Got:", rate, "Expected:", "0.006164")
+	}
+
+	if _, err := fx.CrossRate("JPY", "GBP"); err != portfolio.ErrNoFXPath {
+		t.Error("Got:", err, "Expected:", portfolio.ErrNoFXPath)
+	}
+}
+
+func TestRebalanceConvertsForeignCurrency(t *testing.T) {
+	fx := portfolio.NewFXTable()
+	if err := fx.SetRate("EUR", "USD", mustDecimal(t, "1.1")); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	acct := portfolio.NewAccount(false, false)
+	acct.SetFXTable(fx)
+	acct.ReportCurrency("USD")
+	acct.SetLimits(mustDecimal(t, "100"), mustDecimal(t, "100"), mustDecimal(t, "100"))
+	acct.SetRebalThreshold(mustDecimal(t, "0"))
+	acct.SetBalance(mustDecimal(t, "1000"))
+
+	if err := acct.SetActual([]portfolio.Position{
+		{Sym: "vwce", SecType: portfolio.Stock, Shares: *mustDecimal(t, "100"), Currency: "EUR"},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetDesired([]portfolio.Position{
+		{Sym: "vwce", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50"), Currency: "EUR"},
+		{Sym: "spy", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("vwce", "10"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("spy", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	plan, err := acct.Rebalance()
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(plan.Trades) != 2 {
+		t.Fatalf("Got %d trades, expected 2", len(plan.Trades))
+	}
+
+	bySym := make(map[string]portfolio.Trade, len(plan.Trades))
+	for _, tr := range plan.Trades {
+		bySym[tr.Sym] = tr
+	}
+
+	// vwce is worth 100*10*1.1 = 1100 USD total, half of which (550 USD = 50 shares at 11
+	// USD-equivalent each) must be sold to get it down to its 50% target
+	vwce, ok := bySym["vwce"]
+	if !ok || vwce.Side != portfolio.Sell || !vwce.Shares.EQ(mustDecimal(t, "50")) {
+		t.Errorf("Got vwce trade %+v, expected a 50 share sell", vwce)
+	}
+
+	// spy should be bought with 550 USD of the account's cash balance, at $1/share
+	spy, ok := bySym["spy"]
+	if !ok || spy.Side != portfolio.Buy || !spy.Shares.EQ(mustDecimal(t, "550")) {
+		t.Errorf("Got spy trade %+v, expected a 550 share buy", spy)
+	}
+}
+
+func TestRebalanceNoFXPath(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+	acct.SetLimits(mustDecimal(t, "100"), mustDecimal(t, "100"), mustDecimal(t, "100"))
+	acct.SetRebalThreshold(mustDecimal(t, "0"))
+
+	if err := acct.SetActual([]portfolio.Position{
+		{Sym: "vwce", SecType: portfolio.Stock, Shares: *mustDecimal(t, "100"), Currency: "EUR"},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("vwce", "10"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	if _, err := acct.Rebalance(); err != portfolio.ErrNoFXPath {
+		t.Error("Got:", err, "Expected:", portfolio.ErrNoFXPath)
+	}
+}
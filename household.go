@@ -0,0 +1,194 @@
+package portfolio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nu11ptr/decimal"
+)
+
+// Household groups the accounts belonging to a single investor so that one desired allocation
+// can be placed across accounts for optimal tax efficiency, rather than targeted account by
+// account.
+type Household struct {
+	accounts []*Account
+	desired  map[string]Position
+	mut      sync.Mutex
+
+	// AllowTransfers permits Rebalance, after every account's plan has been applied, to move cash
+	// from accounts with a positive balance to any account whose balance is negative. Each
+	// account's buys are capped by its own cash balance whether or not this is set; a transfer
+	// does not enlarge any plan's trades.
+	AllowTransfers bool
+}
+
+// NewHousehold creates a new Household holding accounts in the given order. Placement prefers
+// earlier accounts when more than one is eligible to hold a given symbol.
+func NewHousehold(accounts ...*Account) *Household {
+	return &Household{accounts: accounts, desired: make(map[string]Position, maxPos)}
+}
+
+// SetDesired sets the single desired allocation the household, as a whole, targets
+func (h *Household) SetDesired(p []Position) error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	desired := make(map[string]Position, maxPos)
+	if err := setPositions(desired, p, false); err != nil {
+		return err
+	}
+	h.desired = desired
+	return nil
+}
+
+// place chooses the account that should hold pos, preferring NonTaxable accounts for
+// HighYield/OrdinaryIncome positions, taxable accounts for TaxExempt/Growth positions, and an
+// account already holding the symbol otherwise. Short positions are restricted to Margin
+// accounts.
+func (h *Household) place(pos Position) (*Account, error) {
+	if pos.Short {
+		for _, acc := range h.accounts {
+			if acc.Margin {
+				return acc, nil
+			}
+		}
+		return nil, ErrNoMarginAccount
+	}
+
+	switch pos.TaxEfficiency {
+	case HighYield, OrdinaryIncome:
+		for _, acc := range h.accounts {
+			if acc.NonTaxable {
+				return acc, nil
+			}
+		}
+	case TaxExempt, Growth:
+		for _, acc := range h.accounts {
+			if !acc.NonTaxable {
+				return acc, nil
+			}
+		}
+	}
+
+	for _, acc := range h.accounts {
+		if acc.hasSymbol(pos.Sym) {
+			return acc, nil
+		}
+	}
+
+	return h.accounts[0], nil
+}
+
+// Rebalance decides which account should hold each desired position, sets each account's desired
+// allocation (renormalized to 100% of that account), and rebalances every account in turn. If
+// AllowTransfers is set, any shortfall left in an account's cash balance is covered by a Transfer
+// from another account with spare cash.
+func (h *Household) Rebalance() (map[*Account]*SwapPlan, error) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if len(h.accounts) == 0 {
+		return nil, ErrNoAccounts
+	}
+
+	assigned := make(map[*Account][]Position, len(h.accounts))
+	for _, pos := range h.desired {
+		acc, err := h.place(pos)
+		if err != nil {
+			return nil, err
+		}
+		assigned[acc] = append(assigned[acc], pos)
+	}
+
+	for acc, positions := range assigned {
+		total := decimal.NewInt(0)
+		for _, pos := range positions {
+			total = total.Add(&pos.Pct)
+		}
+
+		// Preserve any price already set directly on the account, since placement otherwise
+		// rebuilds its desired positions from scratch on every call
+		existing := acc.Desired()
+
+		normalized := make([]Position, len(positions))
+		for i, pos := range positions {
+			pos.Pct = *pos.Pct.Mul(oneHundred).Div(total)
+			if old, ok := existing[pos.Sym]; ok {
+				pos.Price = old.Price
+			}
+			normalized[i] = pos
+		}
+		if err := acc.SetDesired(normalized); err != nil {
+			return nil, err
+		}
+	}
+
+	plans := make(map[*Account]*SwapPlan, len(h.accounts))
+	for _, acc := range h.accounts {
+		plan, err := acc.Rebalance()
+		if err != nil {
+			return nil, err
+		}
+		plans[acc] = plan
+	}
+
+	if h.AllowTransfers {
+		h.fundShortfalls(plans)
+	}
+
+	return plans, nil
+}
+
+// fundShortfalls moves cash from accounts with a spare balance to accounts left with a negative
+// balance after rebalancing, recording each move as a Transfer on the receiving account's plan.
+func (h *Household) fundShortfalls(plans map[*Account]*SwapPlan) {
+	for _, needy := range h.accounts {
+		needy.mut.Lock()
+		shortfall := needy.balance.Neg()
+		needy.mut.Unlock()
+		if shortfall.LTE(zero) {
+			continue
+		}
+
+		for _, donor := range h.accounts {
+			if donor == needy {
+				continue
+			}
+
+			donor.mut.Lock()
+			if donor.balance.LTE(zero) {
+				donor.mut.Unlock()
+				continue
+			}
+			// snapshot the value before mutating donor.balance, since minDecimal may return a
+			// pointer directly into donor.balance
+			amount := *minDecimal(&donor.balance, shortfall)
+			donor.balance = *donor.balance.Sub(&amount)
+			donor.mut.Unlock()
+
+			needy.mut.Lock()
+			needy.balance = *needy.balance.Add(&amount)
+			needy.mut.Unlock()
+
+			plans[needy].Transfers = append(plans[needy].Transfers, Transfer{From: donor, To: needy, Amount: amount})
+
+			shortfall = shortfall.Sub(&amount)
+			if shortfall.LTE(zero) {
+				break
+			}
+		}
+	}
+}
+
+// RealizedGains returns the realized gain/loss lots sold within the [from, to] window across
+// every account in the household
+func (h *Household) RealizedGains(from, to time.Time) []RealizedLot {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	var gains []RealizedLot
+	for _, acc := range h.accounts {
+		gains = append(gains, acc.RealizedGains(from, to)...)
+	}
+	return gains
+}
@@ -0,0 +1,100 @@
+package portfolio_test
+
+import (
+	"testing"
+
+	portfolio "github.com/nu11ptr/portfolio-swap"
+)
+
+func TestHouseholdPlacesHighYieldInNonTaxable(t *testing.T) {
+	taxable := portfolio.NewAccount(false, false)
+	ira := portfolio.NewAccount(false, true)
+
+	for _, acct := range []*portfolio.Account{taxable, ira} {
+		if err := acct.SetActual(nil); err != nil {
+			t.Fatal("Got:", err, "Expected:", nil)
+		}
+		acct.SetLimits(mustDecimal(t, "100"), mustDecimal(t, "100"), mustDecimal(t, "100"))
+		acct.SetRebalThreshold(mustDecimal(t, "0"))
+		acct.SetBalance(mustDecimal(t, "1000"))
+	}
+
+	household := portfolio.NewHousehold(taxable, ira)
+	if err := household.SetDesired([]portfolio.Position{
+		{Sym: "bond", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50"), TaxEfficiency: portfolio.HighYield},
+		{Sym: "muni", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50"), TaxEfficiency: portfolio.TaxExempt},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	// The first pass places each symbol on an account but fails since no price is set yet
+	if _, err := household.Rebalance(); err != portfolio.ErrBadPrice {
+		t.Fatal("Got:", err, "Expected:", portfolio.ErrBadPrice)
+	}
+
+	iraDesired := ira.Desired()
+	if _, ok := iraDesired["bond"]; !ok {
+		t.Fatal("expected the high-yield bond to be placed in the NonTaxable account")
+	}
+	taxableDesired := taxable.Desired()
+	if _, ok := taxableDesired["muni"]; !ok {
+		t.Fatal("expected the tax-exempt muni to be placed in the taxable account")
+	}
+
+	if err := ira.SetPriceStr("bond", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := taxable.SetPriceStr("muni", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	plans, err := household.Rebalance()
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(plans) != 2 {
+		t.Errorf("Got %d plans, expected 2", len(plans))
+	}
+}
+
+func TestHouseholdFundShortfallsMultiDonor(t *testing.T) {
+	needy := portfolio.NewAccount(false, false)
+	donor1 := portfolio.NewAccount(false, false)
+	donor2 := portfolio.NewAccount(false, false)
+
+	needy.SetBalance(mustDecimal(t, "-150"))
+	donor1.SetBalance(mustDecimal(t, "100"))
+	donor2.SetBalance(mustDecimal(t, "100"))
+
+	household := portfolio.NewHousehold(needy, donor1, donor2)
+	household.AllowTransfers = true
+
+	plans, err := household.Rebalance()
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	transfers := plans[needy].Transfers
+	if len(transfers) != 2 {
+		t.Fatalf("Got %d transfers, expected 2 (donor1 partially covers, donor2 covers the rest)", len(transfers))
+	}
+	if transfers[0].From != donor1 || transfers[0].To != needy || !transfers[0].Amount.EQ(mustDecimal(t, "100")) {
+		t.Errorf("Got transfer 0 %+v, expected 100 from donor1 to needy", transfers[0])
+	}
+	if transfers[1].From != donor2 || transfers[1].To != needy || !transfers[1].Amount.EQ(mustDecimal(t, "50")) {
+		t.Errorf("Got transfer 1 %+v, expected 50 from donor2 to needy", transfers[1])
+	}
+}
+
+func TestHouseholdNoAccounts(t *testing.T) {
+	household := portfolio.NewHousehold()
+	if err := household.SetDesired([]portfolio.Position{
+		{Sym: "bond", SecType: portfolio.Stock, Pct: *mustDecimal(t, "100")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	if _, err := household.Rebalance(); err != portfolio.ErrNoAccounts {
+		t.Error("Got:", err, "Expected:", portfolio.ErrNoAccounts)
+	}
+}
@@ -0,0 +1,215 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nu11ptr/decimal"
+)
+
+// shortTermWindow is the maximum holding period, inclusive, for a realized gain/loss to be
+// considered short-term under the usual one-year rule.
+const shortTermWindow = 365 * 24 * time.Hour
+
+// LotMethod selects the order in which tax lots are consumed by Account.ConsumeShares
+type LotMethod int
+
+const (
+	// FIFO consumes the oldest lots first
+	FIFO LotMethod = iota
+	// LIFO consumes the newest lots first
+	LIFO
+	// HighestCost consumes the highest cost-basis lots first, minimizing realized gain
+	HighestCost
+	// SpecificID consumes lots in the exact order they were added via AddLot, regardless of
+	// acquisition date or the ledger's date-sorted order
+	SpecificID
+)
+
+// Lot represents a single tax lot of shares acquired at a given price and time
+type Lot struct {
+	Shares, Price decimal.Decimal
+	Acquired      time.Time
+
+	// seq records the order lots were added via AddLot, so SpecificID can walk that order even
+	// though the ledger itself is kept sorted by Acquired
+	seq int
+}
+
+// RealizedLot represents the gain or loss realized by consuming all or part of a Lot
+type RealizedLot struct {
+	Sym                       string
+	Shares                    decimal.Decimal
+	CostBasis, Proceeds, Gain decimal.Decimal
+	Acquired, Sold            time.Time
+	ShortTerm                 bool
+}
+
+// AverageCost returns the weighted average cost basis per share across all lots in the position.
+// The second return value is false if the position holds no lots.
+func (p *Position) AverageCost() (decimal.Decimal, bool) {
+	if len(p.Lots) == 0 {
+		return decimal.Decimal{}, false
+	}
+
+	shares, cost := decimal.NewInt(0), decimal.NewInt(0)
+	for _, l := range p.Lots {
+		shares = shares.Add(&l.Shares)
+		cost = cost.Add(l.Shares.Mul(&l.Price))
+	}
+	return *cost.Div(shares), true
+}
+
+func sumLotShares(lots []Lot) decimal.Decimal {
+	total := decimal.NewInt(0)
+	for _, l := range lots {
+		total = total.Add(&l.Shares)
+	}
+	return *total
+}
+
+// insertLot inserts l into lots, keeping the ledger sorted by acquisition date
+func insertLot(lots []Lot, l Lot) []Lot {
+	i := sort.Search(len(lots), func(i int) bool { return lots[i].Acquired.After(l.Acquired) })
+	lots = append(lots, Lot{})
+	copy(lots[i+1:], lots[i:])
+	lots[i] = l
+	return lots
+}
+
+// AddLot records a new tax lot of shares acquired at price for sym, creating the underlying
+// actual position (as a Stock) if one does not already exist.
+func (a *Account) AddLot(sym string, shares, price *decimal.Decimal, acquired time.Time) error {
+	if sym == "" || isCashSym(sym) {
+		return ErrBadSym
+	}
+	if shares.LTE(zero) {
+		return ErrBadNumShares
+	}
+	if price.LTE(zero) {
+		return ErrBadPrice
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	pos, ok := a.actual[sym]
+	if !ok {
+		pos = Position{Sym: sym, SecType: Stock}
+	}
+	a.lotSeq++
+	pos.Lots = insertLot(pos.Lots, Lot{Shares: *shares, Price: *price, Acquired: acquired, seq: a.lotSeq})
+	pos.Shares = sumLotShares(pos.Lots)
+	a.actual[sym] = pos
+
+	return nil
+}
+
+// orderLots returns the indices of lots in the order they should be consumed for method
+func orderLots(lots []Lot, method LotMethod) []int {
+	idx := make([]int, len(lots))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	switch method {
+	case LIFO:
+		sort.SliceStable(idx, func(i, j int) bool { return lots[idx[i]].Acquired.After(lots[idx[j]].Acquired) })
+	case HighestCost:
+		sort.SliceStable(idx, func(i, j int) bool { return lots[idx[i]].Price.GT(&lots[idx[j]].Price) })
+	case SpecificID:
+		sort.SliceStable(idx, func(i, j int) bool { return lots[idx[i]].seq < lots[idx[j]].seq })
+	case FIFO:
+		// lots are already kept sorted by acquisition date
+	}
+	return idx
+}
+
+// ConsumeShares removes qty shares of sym from the tax-lot ledger in the order dictated by
+// method, returning the realized gain or loss for each lot (or partial lot) consumed. It returns
+// ErrSymNotFound if sym has no tax lots and ErrInsufficientShares if the ledger holds fewer than
+// qty shares.
+func (a *Account) ConsumeShares(sym string, qty *decimal.Decimal, method LotMethod) ([]RealizedLot, error) {
+	if qty.LTE(zero) {
+		return nil, ErrBadNumShares
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	pos, ok := a.actual[sym]
+	if !ok || len(pos.Lots) == 0 {
+		return nil, ErrSymNotFound
+	}
+
+	sold := time.Now()
+	order := orderLots(pos.Lots, method)
+	consumed := make(map[int]bool, len(order))
+
+	remaining := *qty
+	var realized []RealizedLot
+
+	for _, i := range order {
+		if remaining.LTE(zero) {
+			break
+		}
+
+		lot := pos.Lots[i]
+		take := lot.Shares
+		if take.GT(&remaining) {
+			take = remaining
+		}
+
+		cost := take.Mul(&lot.Price)
+		proceeds := take.Mul(&pos.Price)
+		realized = append(realized, RealizedLot{
+			Sym:       sym,
+			Shares:    take,
+			CostBasis: *cost,
+			Proceeds:  *proceeds,
+			Gain:      *proceeds.Sub(cost),
+			Acquired:  lot.Acquired,
+			Sold:      sold,
+			ShortTerm: sold.Sub(lot.Acquired) <= shortTermWindow,
+		})
+
+		remaining = *remaining.Sub(&take)
+		if lot.Shares.GT(&take) {
+			lot.Shares = *lot.Shares.Sub(&take)
+			pos.Lots[i] = lot
+		} else {
+			consumed[i] = true
+		}
+	}
+	if remaining.GT(zero) {
+		return nil, ErrInsufficientShares
+	}
+
+	lots := make([]Lot, 0, len(pos.Lots)-len(consumed))
+	for i, l := range pos.Lots {
+		if !consumed[i] {
+			lots = append(lots, l)
+		}
+	}
+	pos.Lots = lots
+	pos.Shares = sumLotShares(lots)
+	a.actual[sym] = pos
+
+	a.realized = append(a.realized, realized...)
+
+	return realized, nil
+}
+
+// RealizedGains returns the realized gain/loss lots sold within the [from, to] window
+func (a *Account) RealizedGains(from, to time.Time) []RealizedLot {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	var gains []RealizedLot
+	for _, r := range a.realized {
+		if !r.Sold.Before(from) && !r.Sold.After(to) {
+			gains = append(gains, r)
+		}
+	}
+	return gains
+}
@@ -0,0 +1,100 @@
+package portfolio_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nu11ptr/decimal"
+	portfolio "github.com/nu11ptr/portfolio-swap"
+)
+
+func mustDecimal(t *testing.T, s string) *decimal.Decimal {
+	t.Helper()
+	d, ok := decimal.New(s)
+	if !ok {
+		t.Fatalf("bad decimal literal: %s", s)
+	}
+	return d
+}
+
+func TestAddLotConsumeSharesFIFO(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := acct.AddLot("bogus", mustDecimal(t, "10"), mustDecimal(t, "100"), jan); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.AddLot("bogus", mustDecimal(t, "10"), mustDecimal(t, "120"), feb); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus", "150"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	realized, err := acct.ConsumeShares("bogus", mustDecimal(t, "15"), portfolio.FIFO)
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(realized) != 2 {
+		t.Fatalf("Got %d realized lots, expected 2", len(realized))
+	}
+	if !realized[0].Acquired.Equal(jan) || !realized[0].Shares.EQ(mustDecimal(t, "10")) {
+		t.Error("expected the Jan lot fully consumed first")
+	}
+	if !realized[1].Acquired.Equal(feb) || !realized[1].Shares.EQ(mustDecimal(t, "5")) {
+		t.Error("expected 5 shares consumed from the Feb lot")
+	}
+
+	remaining := acct.Actual()["bogus"]
+	if !remaining.Shares.EQ(mustDecimal(t, "5")) {
+		t.Error("Got:", remaining.Shares, "Expected:", "5")
+	}
+}
+
+func TestConsumeSharesInsufficient(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := acct.AddLot("bogus", mustDecimal(t, "10"), mustDecimal(t, "100"), jan); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus", "100"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	if _, err := acct.ConsumeShares("bogus", mustDecimal(t, "11"), portfolio.FIFO); err != portfolio.ErrInsufficientShares {
+		t.Error("Got:", err, "Expected:", portfolio.ErrInsufficientShares)
+	}
+	if _, err := acct.ConsumeShares("missing", mustDecimal(t, "1"), portfolio.FIFO); err != portfolio.ErrSymNotFound {
+		t.Error("Got:", err, "Expected:", portfolio.ErrSymNotFound)
+	}
+}
+
+func TestConsumeSharesSpecificID(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	// the Feb lot is added first, so SpecificID should consume it before the Jan lot even though
+	// the ledger itself is kept sorted by acquisition date (oldest first)
+	if err := acct.AddLot("bogus", mustDecimal(t, "10"), mustDecimal(t, "120"), feb); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.AddLot("bogus", mustDecimal(t, "10"), mustDecimal(t, "100"), jan); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus", "150"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	realized, err := acct.ConsumeShares("bogus", mustDecimal(t, "5"), portfolio.SpecificID)
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(realized) != 1 || !realized[0].Acquired.Equal(feb) {
+		t.Error("expected the Feb lot (added first) to be consumed first under SpecificID")
+	}
+}
@@ -2,6 +2,7 @@ package portfolio
 
 import (
 	"errors"
+	"strings"
 	"sync"
 
 	"github.com/nu11ptr/decimal"
@@ -40,6 +41,53 @@ var (
 
 	ErrPctOverflow  = errors.New("Total position percentage cannot exceed 100")
 	ErrPctUnderflow = errors.New("Total position percentage must add up to 100")
+
+	ErrInsufficientShares = errors.New("Not enough shares in the tax-lot ledger to satisfy the request")
+
+	ErrNoAccounts      = errors.New("Household has no accounts")
+	ErrNoMarginAccount = errors.New("No margin account is available to hold a short position")
+
+	ErrNoFXPath = errors.New("No chain of FX rates connects the two currencies")
+)
+
+// defaultCurrency is assumed for any position that doesn't specify one
+const defaultCurrency = "USD"
+
+// isCashSym reports whether sym is the plain cash sentinel or one of its per-currency variants,
+// e.g. "*CASH*:EUR"
+func isCashSym(sym string) bool {
+	return sym == CashSym || strings.HasPrefix(sym, CashSym+":")
+}
+
+// currencyOf returns the default currency for sym: the suffix of a per-currency cash sentinel, or
+// defaultCurrency otherwise
+func currencyOf(sym string) string {
+	if rest, ok := strings.CutPrefix(sym, CashSym+":"); ok {
+		return rest
+	}
+	return defaultCurrency
+}
+
+// TaxEfficiency classifies how tax-efficient a position is to hold in a taxable account, guiding
+// Household asset location
+type TaxEfficiency int
+
+const (
+	// Growth represents a position with little or no taxable distribution, ideal for taxable
+	// accounts where unrealized gains benefit from a step-up in basis
+	Growth TaxEfficiency = iota
+	// QualifiedDividend represents a position distributing qualified dividends, taxed favorably
+	// in either account type
+	QualifiedDividend
+	// TaxExempt represents a position (e.g. municipal bonds) whose distributions are already
+	// tax-exempt, best held in a taxable account to preserve that benefit
+	TaxExempt
+	// HighYield represents a position with high ordinary-income distributions, best sheltered in
+	// a NonTaxable account
+	HighYield
+	// OrdinaryIncome represents a position whose distributions are taxed as ordinary income, best
+	// sheltered in a NonTaxable account
+	OrdinaryIncome
 )
 
 // Position represents an actual or desired position in an account
@@ -47,13 +95,28 @@ type Position struct {
 	Sym                string
 	SecType            SecType
 	Shares, Price, Pct decimal.Decimal
+
+	// TaxEfficiency classifies desired positions for Household asset location; it has no effect
+	// on a standalone Account
+	TaxEfficiency TaxEfficiency
+	// Short indicates the position is (or would be) a short sale, which a Household will only
+	// place into a Margin account
+	Short bool
+
+	// Currency is the ISO code the position is priced in. It defaults to "USD", or, for a
+	// per-currency cash sentinel such as "*CASH*:EUR", to the suffix of Sym.
+	Currency string
+
+	// Lots holds the ordered tax-lot ledger backing Shares, kept sorted by Acquired date.
+	// It is only ever populated for actual positions built up via Account.AddLot.
+	Lots []Lot
 }
 
 func (p *Position) validate(actual bool) error {
-	if p.Sym == "" || (p.SecType == Cash && p.Sym != CashSym) {
+	if p.Sym == "" || (p.SecType == Cash && !isCashSym(p.Sym)) {
 		return ErrBadSym
 	}
-	if p.Sym == CashSym && p.SecType != Cash {
+	if isCashSym(p.Sym) && p.SecType != Cash {
 		return ErrBadSecType
 	}
 	if actual {
@@ -74,6 +137,10 @@ type Account struct {
 	balance, lmtPct, lmtOpenPct, lmtClosepct, rebalThresh decimal.Decimal
 	mut                                                   sync.Mutex
 	sellOnClose                                           bool
+	realized                                              []RealizedLot
+	fxTable                                               *FXTable
+	reportCcy                                             string
+	lotSeq                                                int
 
 	Margin, NonTaxable bool
 }
@@ -102,9 +169,12 @@ func setPositions(m map[string]Position, p []Position, actual bool) error {
 				return ErrPctOverflow
 			}
 		}
-		if pos.Sym == CashSym {
+		if isCashSym(pos.Sym) {
 			pos.Price = *one
 		}
+		if pos.Currency == "" {
+			pos.Currency = currencyOf(pos.Sym)
+		}
 		m[pos.Sym] = pos
 	}
 	if !actual && totalPct.LT(oneHundred) {
@@ -113,13 +183,28 @@ func setPositions(m map[string]Position, p []Position, actual bool) error {
 	return nil
 }
 
-// SetActual sets the actual set of positions for the account
+// SetActual sets the actual set of positions for the account. Tax lots previously recorded via
+// AddLot for a symbol that is still present are preserved, and Shares for that symbol is
+// recomputed from the ledger rather than taken from p.
 func (a *Account) SetActual(p []Position) error {
 	a.mut.Lock()
 	defer a.mut.Unlock()
 
-	a.actual = make(map[string]Position, maxPos)
-	return setPositions(a.actual, p, true)
+	actual := make(map[string]Position, maxPos)
+	if err := setPositions(actual, p, true); err != nil {
+		return err
+	}
+
+	for sym, pos := range actual {
+		if old, ok := a.actual[sym]; ok && len(old.Lots) > 0 {
+			pos.Lots = old.Lots
+			pos.Shares = sumLotShares(old.Lots)
+			actual[sym] = pos
+		}
+	}
+
+	a.actual = actual
+	return nil
 }
 
 // SetDesired sets the desired sets of positions for the account
@@ -139,6 +224,15 @@ func copyMap(m map[string]Position) map[string]Position {
 	return m2
 }
 
+// hasSymbol reports whether sym is currently held as an actual position
+func (a *Account) hasSymbol(sym string) bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	_, ok := a.actual[sym]
+	return ok
+}
+
 // Actual returns a copy of the map storing actual positions
 func (a *Account) Actual() map[string]Position {
 	a.mut.Lock()
@@ -168,7 +262,7 @@ func setPrice(m map[string]Position, sym string, price *decimal.Decimal) bool {
 // SetPrice sets the price on the symbol specified. It returns an error if the price or symbol
 // is invalid or if the symbol cannot be found
 func (a *Account) SetPrice(sym string, price *decimal.Decimal) error {
-	if sym == "" || sym == CashSym {
+	if sym == "" || isCashSym(sym) {
 		return ErrBadSym
 	}
 	if price.LTE(zero) {
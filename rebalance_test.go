@@ -0,0 +1,133 @@
+package portfolio_test
+
+import (
+	"testing"
+
+	portfolio "github.com/nu11ptr/portfolio-swap"
+)
+
+func setupRebalanceAcct(t *testing.T) *portfolio.Account {
+	t.Helper()
+
+	acct := portfolio.NewAccount(false, false)
+	if err := acct.SetActual([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Shares: *mustDecimal(t, "80")},
+		{Sym: "bogus2", SecType: portfolio.Stock, Shares: *mustDecimal(t, "20")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetDesired([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50")},
+		{Sym: "bogus2", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus2", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	acct.SetLimits(mustDecimal(t, "100"), mustDecimal(t, "100"), mustDecimal(t, "100"))
+	acct.SetRebalThreshold(mustDecimal(t, "1"))
+	acct.SetBalance(mustDecimal(t, "1000"))
+
+	return acct
+}
+
+func TestRebalance(t *testing.T) {
+	acct := setupRebalanceAcct(t)
+
+	plan, err := acct.Rebalance()
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if len(plan.Trades) != 2 {
+		t.Fatalf("Got %d trades, expected 2", len(plan.Trades))
+	}
+
+	actual := acct.Actual()
+	bogus, bogus2 := actual["bogus"], actual["bogus2"]
+	if !bogus.Shares.EQ(mustDecimal(t, "50")) {
+		t.Error("Got:", bogus.Shares, "Expected:", "50")
+	}
+	if !bogus2.Shares.EQ(mustDecimal(t, "50")) {
+		t.Error("Got:", bogus2.Shares, "Expected:", "50")
+	}
+}
+
+func TestRebalanceBuyCappedByBalance(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+	if err := acct.SetActual([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Shares: *mustDecimal(t, "90")},
+		{Sym: "bogus2", SecType: portfolio.Stock, Shares: *mustDecimal(t, "10")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetDesired([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50")},
+		{Sym: "bogus2", SecType: portfolio.Stock, Pct: *mustDecimal(t, "50")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetPriceStr("bogus2", "1"); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	acct.SetLimits(mustDecimal(t, "100"), mustDecimal(t, "100"), mustDecimal(t, "100"))
+	acct.SetRebalThreshold(mustDecimal(t, "1"))
+	// bogus2 needs a $40 buy to reach 50%, but only $10 of cash is on hand
+	acct.SetBalance(mustDecimal(t, "10"))
+
+	plan, err := acct.Rebalance()
+	if err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	var buy *portfolio.Trade
+	for i, tr := range plan.Trades {
+		if tr.Side == portfolio.Buy {
+			buy = &plan.Trades[i]
+		}
+	}
+	if buy == nil {
+		t.Fatal("expected a buy trade for bogus2")
+	}
+	if !buy.Shares.EQ(mustDecimal(t, "10")) {
+		t.Errorf("Got buy of %v shares, expected 10 (capped by the $10 balance)", buy.Shares)
+	}
+}
+
+func TestRebalanceNoPrice(t *testing.T) {
+	acct := portfolio.NewAccount(false, false)
+	if err := acct.SetActual([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Shares: *mustDecimal(t, "80")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+	if err := acct.SetDesired([]portfolio.Position{
+		{Sym: "bogus", SecType: portfolio.Stock, Pct: *mustDecimal(t, "100")},
+	}); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	if _, err := acct.Rebalance(); err != portfolio.ErrBadPrice {
+		t.Error("Got:", err, "Expected:", portfolio.ErrBadPrice)
+	}
+}
+
+func TestDryRunDoesNotMutate(t *testing.T) {
+	acct := setupRebalanceAcct(t)
+
+	if _, err := acct.DryRun(); err != nil {
+		t.Fatal("Got:", err, "Expected:", nil)
+	}
+
+	actual := acct.Actual()
+	bogus := actual["bogus"]
+	if !bogus.Shares.EQ(mustDecimal(t, "80")) {
+		t.Error("DryRun mutated actual shares. Got:", bogus.Shares, "Expected:", "80")
+	}
+}